@@ -0,0 +1,173 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+import (
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SparkApplication represents a Spark application running on and using Kubernetes as a cluster manager.
+type SparkApplication struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              SparkApplicationSpec   `json:"spec"`
+	Status            SparkApplicationStatus `json:"status,omitempty"`
+}
+
+// SparkApplicationSpec describes the specification of a Spark application using Kubernetes as a cluster manager.
+type SparkApplicationSpec struct {
+	// SparkConf carries user-specified Spark configuration properties as they would use the "--conf" option
+	// in spark-submit.
+	// +optional
+	SparkConf map[string]string `json:"sparkConf,omitempty"`
+	// SparkUIOptions allows configuring the Service and Ingress used to expose the Spark UI.
+	// +optional
+	SparkUIOptions *SparkUIOptions `json:"sparkUIOptions,omitempty"`
+	// DriverServiceOptions allows configuring the Service that fronts the driver pod, beyond the Spark UI
+	// port managed through SparkUIOptions.
+	// +optional
+	DriverServiceOptions *DriverServiceOptions `json:"driverServiceOptions,omitempty"`
+}
+
+// DriverServiceOptions configures the Service created in front of the driver pod.
+type DriverServiceOptions struct {
+	// Headless requests that the driver Service be created with ClusterIP: None, so executors and
+	// monitoring agents can resolve the driver pod directly via DNS instead of going through kube-proxy.
+	// +optional
+	Headless bool `json:"headless,omitempty"`
+	// PublishNotReadyAddresses requests that the driver Service publish the driver pod's address before
+	// it's ready, so scrapers (e.g. Prometheus) can reach the driver's metrics endpoints before the Spark
+	// UI readiness probe passes.
+	// +optional
+	PublishNotReadyAddresses bool `json:"publishNotReadyAddresses,omitempty"`
+}
+
+// SparkApplicationStatus describes the current status of a Spark application.
+type SparkApplicationStatus struct {
+	// DriverInfo holds information about the driver.
+	DriverInfo DriverInfo `json:"driverInfo,omitempty"`
+}
+
+// DriverInfo captures information about the driver.
+type DriverInfo struct {
+	WebUIServiceName    string `json:"webUIServiceName,omitempty"`
+	WebUIPort           int32  `json:"webUIPort,omitempty"`
+	WebUIAddress        string `json:"webUIAddress,omitempty"`
+	WebUIIngressName    string `json:"webUIIngressName,omitempty"`
+	WebUIIngressAddress string `json:"webUIIngressAddress,omitempty"`
+	PodName             string `json:"podName,omitempty"`
+}
+
+// SparkUIOptions is the configuration for the Spark UI Service and Ingress created for a SparkApplication.
+type SparkUIOptions struct {
+	// ServicePort allows configuring the port at which the SparkUI service is exposed.
+	// +optional
+	ServicePort *int32 `json:"servicePort,omitempty"`
+	// ServicePortName allows configuring the name of the SparkUI service port.
+	// +optional
+	ServicePortName *string `json:"servicePortName,omitempty"`
+	// IngressAnnotations allows configuring the annotations applied to the SparkUI ingress.
+	// +optional
+	IngressAnnotations map[string]string `json:"ingressAnnotations,omitempty"`
+	// IngressTLS allows configuring the TLS configuration of the SparkUI ingress.
+	// +optional
+	IngressTLS []networkingv1.IngressTLS `json:"ingressTLS,omitempty"`
+	// IngressClassName sets the Ingress.Spec.IngressClassName field of the SparkUI ingress, selecting the
+	// IngressClass that should reconcile it. Only used when the networking.k8s.io/v1 Ingress API is available.
+	// +optional
+	IngressClassName *string `json:"ingressClassName,omitempty"`
+	// IngressFlavor selects the ingress controller flavor that the generated annotations should target, e.g.
+	// "nginx", "traefik", "gce" or "contour". Defaults to "nginx" for backwards compatibility.
+	// +optional
+	IngressFlavor *string `json:"ingressFlavor,omitempty"`
+	// TLS requests that the operator provision TLS for the SparkUI ingress via cert-manager, in addition to
+	// (or instead of) any entries set directly in IngressTLS.
+	// +optional
+	TLS *SparkUITLSOptions `json:"tls,omitempty"`
+	// Auth configures authentication in front of the SparkUI ingress. Exactly one of Basic, OAuth2Proxy may
+	// be set; IPWhitelist may be combined with either.
+	// +optional
+	Auth *SparkUIAuthOptions `json:"auth,omitempty"`
+	// PublishedService points at an arbitrary "namespace/name" Service (e.g. a cluster's shared ingress
+	// controller LoadBalancer) whose resolved external address should be copied onto this application's
+	// status and ingress, instead of waiting on the SparkUI Service's own LoadBalancer status. Borrowed
+	// from the "publishedService" pattern used by ingress controllers that front many Services through one
+	// external load balancer.
+	// +optional
+	PublishedService *string `json:"publishedService,omitempty"`
+}
+
+// SparkUIAuthOptions configures authentication for the SparkUI ingress.
+type SparkUIAuthOptions struct {
+	// Basic requests HTTP Basic authentication backed by a Secret containing htpasswd-formatted data.
+	// +optional
+	Basic *BasicAuthOptions `json:"basic,omitempty"`
+	// OAuth2Proxy requests forward-auth to an external oauth2-proxy (or compatible) deployment.
+	// +optional
+	OAuth2Proxy *OAuth2ProxyAuthOptions `json:"oauth2Proxy,omitempty"`
+	// IPWhitelist restricts access to the SparkUI ingress to the given list of CIDRs.
+	// +optional
+	IPWhitelist []string `json:"ipWhitelist,omitempty"`
+}
+
+// BasicAuthOptions configures HTTP Basic authentication for the SparkUI ingress.
+type BasicAuthOptions struct {
+	// SecretName is the name of the Secret holding the htpasswd file used to authenticate requests, under
+	// the key named by SecretKey. If Username and Password are also set, the operator creates and keeps this
+	// Secret up to date; otherwise the Secret is assumed to already exist.
+	SecretName string `json:"secretName"`
+	// SecretKey is the key within SecretName containing the htpasswd data. Defaults to "auth".
+	// +optional
+	SecretKey *string `json:"secretKey,omitempty"`
+	// Username, if set along with Password, is rendered into an htpasswd entry and used to create/update
+	// SecretName. Omit both to manage the Secret's contents yourself.
+	// +optional
+	Username *string `json:"username,omitempty"`
+	// Password is the plaintext password for Username. Only used together with Username.
+	// +optional
+	Password *string `json:"password,omitempty"`
+}
+
+// OAuth2ProxyAuthOptions configures forward-auth to an external oauth2-proxy-compatible service for the
+// SparkUI ingress.
+type OAuth2ProxyAuthOptions struct {
+	// AuthURL is the URL the ingress controller forwards requests to for authentication, e.g.
+	// "https://oauth2-proxy.example.com/oauth2/auth".
+	AuthURL string `json:"authURL"`
+	// SigninURL is the URL unauthenticated users are redirected to in order to sign in, e.g.
+	// "https://oauth2-proxy.example.com/oauth2/start".
+	SigninURL string `json:"signinURL"`
+}
+
+// SparkUITLSOptions configures cert-manager-issued TLS for the SparkUI ingress.
+type SparkUITLSOptions struct {
+	// IssuerKind is the kind of the cert-manager issuer to request the certificate from, e.g. "ClusterIssuer"
+	// or "Issuer". Defaults to "ClusterIssuer".
+	// +optional
+	IssuerKind *string `json:"issuerKind,omitempty"`
+	// IssuerName is the name of the cert-manager issuer (or cluster issuer) to request the certificate from.
+	IssuerName string `json:"issuerName"`
+	// ExtraSANs lists additional DNS names that should be included on the issued certificate, beyond the
+	// ingress's own host.
+	// +optional
+	ExtraSANs []string `json:"extraSANs,omitempty"`
+	// ForceHTTPSRedirect requests that the ingress controller redirect plain HTTP requests to HTTPS, via
+	// the flavor's equivalent of nginx.ingress.kubernetes.io/ssl-redirect.
+	// +optional
+	ForceHTTPSRedirect bool `json:"forceHTTPSRedirect,omitempty"`
+}
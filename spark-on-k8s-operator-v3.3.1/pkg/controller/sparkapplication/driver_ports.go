@@ -0,0 +1,159 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+const (
+	driverRPCPortConfigurationKey     = "spark.driver.port"
+	blockManagerPortConfigurationKey  = "spark.blockManager.port"
+	prometheusEnabledConfigurationKey = "spark.ui.prometheus.enabled"
+	driverExtraJavaOptionsKey         = "spark.driver.extraJavaOptions"
+
+	driverRPCPortName    = "driver-rpc-port"
+	blockManagerPortName = "block-manager"
+	prometheusPortName   = "metrics"
+	jmxPortName          = "jmx-port"
+
+	defaultPrometheusPort int32 = 7078
+)
+
+// jmxRemotePortPattern matches the JVM system property used to pin the driver's JMX remote port, as
+// set via spark.driver.extraJavaOptions, e.g. "-Dcom.sun.management.jmxremote.port=5555".
+var jmxRemotePortPattern = regexp.MustCompile(`-Dcom\.sun\.management\.jmxremote\.port=(\d+)`)
+
+// metricsSinkPortPattern matches spark.metrics.conf.* properties that configure a metrics sink's own
+// listen port, e.g. "spark.metrics.conf.*.sink.graphite.port" or
+// "spark.metrics.conf.driver.sink.statsd.port".
+var metricsSinkPortPattern = regexp.MustCompile(`^spark\.metrics\.conf\.[^.]+\.sink\.([^.]+)\.port$`)
+
+// getDriverNamedPorts derives the named ports that should be added to the driver Service in addition
+// to the Spark UI port, based on the SparkConf properties the user supplied: spark.driver.port,
+// spark.blockManager.port, spark.ui.prometheus.enabled, a JMX remote port pinned via
+// spark.driver.extraJavaOptions, and any spark.metrics.conf.*.sink.<name>.port entries. Ports only
+// become reachable on the Service if the driver itself was configured to listen on them (e.g. setting
+// spark.driver.port pins the driver's RPC endpoint to that port instead of letting Spark pick one at
+// random), so this only ever adds ports for configuration the user already set explicitly.
+func getDriverNamedPorts(app *v1beta2.SparkApplication) ([]apiv1.ServicePort, error) {
+	var ports []apiv1.ServicePort
+
+	if portStr, ok := app.Spec.SparkConf[driverRPCPortConfigurationKey]; ok {
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return nil, err
+		}
+		ports = append(ports, namedServicePort(driverRPCPortName, int32(port)))
+	}
+
+	if portStr, ok := app.Spec.SparkConf[blockManagerPortConfigurationKey]; ok {
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return nil, err
+		}
+		ports = append(ports, namedServicePort(blockManagerPortName, int32(port)))
+	}
+
+	if app.Spec.SparkConf[prometheusEnabledConfigurationKey] == "true" {
+		ports = append(ports, namedServicePort(prometheusPortName, defaultPrometheusPort))
+	}
+
+	if port, ok, err := getJMXPort(app); err != nil {
+		return nil, err
+	} else if ok {
+		ports = append(ports, namedServicePort(jmxPortName, port))
+	}
+
+	metricsPorts, err := getMetricsSinkPorts(app)
+	if err != nil {
+		return nil, err
+	}
+	ports = append(ports, metricsPorts...)
+
+	return ports, nil
+}
+
+// getJMXPort extracts the driver's JMX remote port from spark.driver.extraJavaOptions, if the user
+// pinned one via -Dcom.sun.management.jmxremote.port. It returns ok=false if no such option is set.
+func getJMXPort(app *v1beta2.SparkApplication) (int32, bool, error) {
+	match := jmxRemotePortPattern.FindStringSubmatch(app.Spec.SparkConf[driverExtraJavaOptionsKey])
+	if match == nil {
+		return 0, false, nil
+	}
+	port, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, false, err
+	}
+	return int32(port), true, nil
+}
+
+// getMetricsSinkPorts derives a named Service port for every spark.metrics.conf.*.sink.<name>.port
+// property the user configured, e.g. enabling the Graphite or StatsD sinks with an explicit port. Keys
+// are sorted so the resulting port list is deterministic across reconciles.
+func getMetricsSinkPorts(app *v1beta2.SparkApplication) ([]apiv1.ServicePort, error) {
+	var keys []string
+	for key := range app.Spec.SparkConf {
+		if metricsSinkPortPattern.MatchString(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var ports []apiv1.ServicePort
+	for _, key := range keys {
+		sinkName := metricsSinkPortPattern.FindStringSubmatch(key)[1]
+		port, err := strconv.Atoi(app.Spec.SparkConf[key])
+		if err != nil {
+			return nil, err
+		}
+		ports = append(ports, namedServicePort(fmt.Sprintf("metrics-%s", sinkName), int32(port)))
+	}
+	return ports, nil
+}
+
+func namedServicePort(name string, port int32) apiv1.ServicePort {
+	return apiv1.ServicePort{
+		Name: name,
+		Port: port,
+		TargetPort: intstr.IntOrString{
+			Type:   intstr.Int,
+			IntVal: port,
+		},
+	}
+}
+
+// isDriverServiceHeadless reports whether the driver Service should be created with ClusterIP: None,
+// so executors and scrapers can resolve the driver pod's address directly instead of going through a
+// cluster IP.
+func isDriverServiceHeadless(app *v1beta2.SparkApplication) bool {
+	return app.Spec.DriverServiceOptions != nil && app.Spec.DriverServiceOptions.Headless
+}
+
+// shouldPublishNotReadyAddresses reports whether the driver Service should publish the driver pod's
+// address before its readiness probe (tied to the Spark UI) passes.
+func shouldPublishNotReadyAddresses(app *v1beta2.SparkApplication) bool {
+	return app.Spec.DriverServiceOptions != nil && app.Spec.DriverServiceOptions.PublishNotReadyAddresses
+}
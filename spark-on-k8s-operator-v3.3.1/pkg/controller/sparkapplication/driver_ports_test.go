@@ -0,0 +1,93 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func newTestAppWithSparkConf(sparkConf map[string]string) *v1beta2.SparkApplication {
+	return &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+		Spec:       v1beta2.SparkApplicationSpec{SparkConf: sparkConf},
+	}
+}
+
+func TestGetDriverNamedPortsDerivesAllPorts(t *testing.T) {
+	app := newTestAppWithSparkConf(map[string]string{
+		"spark.driver.port":                          "7078",
+		"spark.blockManager.port":                    "7079",
+		"spark.ui.prometheus.enabled":                "true",
+		"spark.driver.extraJavaOptions":              "-Dcom.sun.management.jmxremote.port=5555",
+		"spark.metrics.conf.*.sink.graphite.port":    "2003",
+		"spark.metrics.conf.driver.sink.statsd.port": "8125",
+	})
+
+	ports, err := getDriverNamedPorts(app)
+	if err != nil {
+		t.Fatalf("getDriverNamedPorts returned error: %v", err)
+	}
+
+	byName := map[string]int32{}
+	for _, p := range ports {
+		byName[p.Name] = p.Port
+	}
+
+	want := map[string]int32{
+		driverRPCPortName:    7078,
+		blockManagerPortName: 7079,
+		prometheusPortName:   defaultPrometheusPort,
+		jmxPortName:          5555,
+		"metrics-graphite":   2003,
+		"metrics-statsd":     8125,
+	}
+	for name, port := range want {
+		got, ok := byName[name]
+		if !ok {
+			t.Errorf("expected a port named %q, got ports %v", name, byName)
+			continue
+		}
+		if got != port {
+			t.Errorf("port %q: expected %d, got %d", name, port, got)
+		}
+	}
+	if len(ports) != len(want) {
+		t.Errorf("expected exactly %d ports, got %d: %v", len(want), len(ports), byName)
+	}
+}
+
+func TestGetDriverNamedPortsEmptyWhenUnconfigured(t *testing.T) {
+	app := newTestAppWithSparkConf(nil)
+	ports, err := getDriverNamedPorts(app)
+	if err != nil {
+		t.Fatalf("getDriverNamedPorts returned error: %v", err)
+	}
+	if len(ports) != 0 {
+		t.Errorf("expected no derived ports, got %v", ports)
+	}
+}
+
+func TestGetDriverNamedPortsInvalidPortValue(t *testing.T) {
+	app := newTestAppWithSparkConf(map[string]string{"spark.driver.port": "not-a-number"})
+	if _, err := getDriverNamedPorts(app); err == nil {
+		t.Error("expected an error for a non-numeric spark.driver.port, got nil")
+	}
+}
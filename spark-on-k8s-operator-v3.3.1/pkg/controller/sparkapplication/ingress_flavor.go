@@ -0,0 +1,199 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"strings"
+
+	networkingv1 "k8s.io/api/networking/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+// IngressFlavor identifies an ingress controller implementation whose annotation conventions
+// createSparkUIIngress should speak.
+type IngressFlavor string
+
+const (
+	// IngressFlavorNginx targets ingress-nginx. This is the default flavor, matching the
+	// annotations the operator has always emitted.
+	IngressFlavorNginx IngressFlavor = "nginx"
+	// IngressFlavorTraefik targets Traefik's Kubernetes ingress provider.
+	IngressFlavorTraefik IngressFlavor = "traefik"
+	// IngressFlavorGCE targets GKE's native GCE ingress controller.
+	IngressFlavorGCE IngressFlavor = "gce"
+	// IngressFlavorContour targets Project Contour.
+	IngressFlavorContour IngressFlavor = "contour"
+
+	defaultIngressFlavor = IngressFlavorNginx
+)
+
+// rewriteAnnotations returns the annotations this flavor requires to rewrite requests received on
+// subPath back to "/" before they reach the Spark UI, which has no knowledge of the path it's served
+// under. It returns nil if the flavor needs no rewrite annotations for serving on a subpath (e.g. GCE,
+// which rewrites based on the backend config instead of annotations and is handled out of band).
+func (f IngressFlavor) rewriteAnnotations(subPath string) map[string]string {
+	if subPath == "" || subPath == "/" {
+		return nil
+	}
+	switch f {
+	case IngressFlavorTraefik:
+		return map[string]string{
+			"traefik.ingress.kubernetes.io/rewrite-target": "/",
+		}
+	case IngressFlavorContour:
+		return map[string]string{
+			"projectcontour.io/rewrite-target": "/",
+		}
+	case IngressFlavorGCE:
+		return nil
+	case IngressFlavorNginx:
+		fallthrough
+	default:
+		return map[string]string{
+			"nginx.ingress.kubernetes.io/rewrite-target": "/$2",
+		}
+	}
+}
+
+// forceHTTPSRedirectAnnotations returns the annotations this flavor requires to redirect plain HTTP
+// requests to HTTPS.
+func (f IngressFlavor) forceHTTPSRedirectAnnotations() map[string]string {
+	switch f {
+	case IngressFlavorTraefik:
+		return map[string]string{
+			"traefik.ingress.kubernetes.io/redirect-entry-point": "https",
+		}
+	case IngressFlavorContour:
+		return map[string]string{
+			"ingress.kubernetes.io/force-ssl-redirect": "true",
+		}
+	case IngressFlavorGCE:
+		return nil
+	case IngressFlavorNginx:
+		fallthrough
+	default:
+		return map[string]string{
+			"nginx.ingress.kubernetes.io/ssl-redirect": "true",
+		}
+	}
+}
+
+// basicAuthAnnotations returns the annotations this flavor requires to enforce HTTP Basic
+// authentication backed by the named Secret. It returns nil for flavors (like GCE) that don't support
+// annotation-driven Basic auth at all.
+func (f IngressFlavor) basicAuthAnnotations(secretName string) map[string]string {
+	switch f {
+	case IngressFlavorNginx:
+		return map[string]string{
+			"nginx.ingress.kubernetes.io/auth-type":   "basic",
+			"nginx.ingress.kubernetes.io/auth-secret": secretName,
+			"nginx.ingress.kubernetes.io/auth-realm":  "Spark UI - authentication required",
+		}
+	case IngressFlavorTraefik:
+		return map[string]string{
+			"traefik.ingress.kubernetes.io/auth-type":   "basic",
+			"traefik.ingress.kubernetes.io/auth-secret": secretName,
+		}
+	default:
+		return nil
+	}
+}
+
+// forwardAuthAnnotations returns the annotations this flavor requires to forward authentication
+// decisions to an external oauth2-proxy-compatible service.
+func (f IngressFlavor) forwardAuthAnnotations(authURL, signinURL string) map[string]string {
+	switch f {
+	case IngressFlavorNginx:
+		return map[string]string{
+			"nginx.ingress.kubernetes.io/auth-url":    authURL,
+			"nginx.ingress.kubernetes.io/auth-signin": signinURL,
+		}
+	case IngressFlavorContour:
+		return map[string]string{
+			"projectcontour.io/auth-url":    authURL,
+			"projectcontour.io/auth-signin": signinURL,
+		}
+	default:
+		return nil
+	}
+}
+
+// whitelistAnnotations returns the annotations this flavor requires to restrict access to the given
+// CIDR ranges.
+func (f IngressFlavor) whitelistAnnotations(cidrs []string) map[string]string {
+	if len(cidrs) == 0 {
+		return nil
+	}
+	joined := strings.Join(cidrs, ",")
+	switch f {
+	case IngressFlavorNginx:
+		return map[string]string{
+			"nginx.ingress.kubernetes.io/whitelist-source-range": joined,
+		}
+	case IngressFlavorTraefik:
+		return map[string]string{
+			"traefik.ingress.kubernetes.io/whitelist-source-range": joined,
+		}
+	default:
+		return nil
+	}
+}
+
+// subPathPattern returns the path that should be used in the Ingress rule for the given requested
+// subPath, rewriting it into the capture-group form the flavor expects when one is required.
+func (f IngressFlavor) subPathPattern(subPath string) string {
+	if subPath == "" {
+		// PathTypePrefix/PathTypeExact require an absolute path; "" is only valid for
+		// PathTypeImplementationSpecific, which not every flavor uses. Default to "/" so the common
+		// no-subpath configuration doesn't get rejected by the API server on flavors that need a
+		// leading slash.
+		return "/"
+	}
+	if subPath == "/" {
+		return subPath
+	}
+	switch f {
+	case IngressFlavorNginx:
+		return subPath + "(/|$)(.*)"
+	default:
+		return subPath
+	}
+}
+
+// pathType returns the PathType that should be used for the Ingress rule of this flavor.
+func (f IngressFlavor) pathType() networkingv1.PathType {
+	switch f {
+	case IngressFlavorGCE:
+		return networkingv1.PathTypeImplementationSpecific
+	default:
+		return networkingv1.PathTypePrefix
+	}
+}
+
+func getIngressFlavor(app *v1beta2.SparkApplication) IngressFlavor {
+	if app.Spec.SparkUIOptions == nil || app.Spec.SparkUIOptions.IngressFlavor == nil {
+		return defaultIngressFlavor
+	}
+	flavor := IngressFlavor(*app.Spec.SparkUIOptions.IngressFlavor)
+	switch flavor {
+	case IngressFlavorNginx, IngressFlavorTraefik, IngressFlavorGCE, IngressFlavorContour:
+		return flavor
+	default:
+		return defaultIngressFlavor
+	}
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"strings"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+// TestSubPathPatternDefaultsToRootForPrefixTypes guards against regressing the common no-subpath
+// configuration: Kubernetes rejects networking.k8s.io/v1 Ingress rules with an empty Path when
+// PathType is Prefix or Exact, so an empty subPath must never be passed through as "".
+func TestSubPathPatternDefaultsToRootForPrefixTypes(t *testing.T) {
+	for _, flavor := range []IngressFlavor{IngressFlavorNginx, IngressFlavorTraefik, IngressFlavorContour, IngressFlavorGCE} {
+		path := flavor.subPathPattern("")
+		if path == "" {
+			t.Errorf("flavor %s: subPathPattern(\"\") returned empty path, which is invalid for PathType %s", flavor, flavor.pathType())
+		}
+		if flavor.pathType() != networkingv1.PathTypeImplementationSpecific && !strings.HasPrefix(path, "/") {
+			t.Errorf("flavor %s: subPathPattern(\"\") = %q must start with \"/\" for PathType %s", flavor, path, flavor.pathType())
+		}
+	}
+}
+
+func TestSubPathPatternPreservesRoot(t *testing.T) {
+	if path := IngressFlavorNginx.subPathPattern("/"); path != "/" {
+		t.Errorf("expected subPathPattern(\"/\") to return \"/\", got %q", path)
+	}
+}
+
+func TestSubPathPatternNginxUsesCaptureGroups(t *testing.T) {
+	path := IngressFlavorNginx.subPathPattern("/spark")
+	if path != "/spark(/|$)(.*)" {
+		t.Errorf("unexpected nginx subpath pattern: %q", path)
+	}
+}
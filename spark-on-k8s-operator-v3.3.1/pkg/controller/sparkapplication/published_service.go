@@ -0,0 +1,124 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/golang/glog"
+
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+// getPublishedServiceSource returns the Service whose LoadBalancer status should be copied onto the
+// application, following the "publishedService" pattern borrowed from ingress controllers: by default
+// that's the application's own Spark UI Service, but SparkUIOptions.PublishedService can point at an
+// arbitrary "namespace/name" Service instead (e.g. a cluster's shared nginx LoadBalancer), so
+// applications behind a single externally-reachable address still get a stable URL.
+func getPublishedServiceSource(app *v1beta2.SparkApplication, kubeClient clientset.Interface) (*apiv1.Service, error) {
+	if app.Spec.SparkUIOptions != nil && app.Spec.SparkUIOptions.PublishedService != nil {
+		ref := *app.Spec.SparkUIOptions.PublishedService
+		parts := strings.SplitN(ref, "/", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("publishedService %q must be of the form \"namespace/name\"", ref)
+		}
+		svc, err := kubeClient.CoreV1().Services(parts[0]).Get(context.TODO(), parts[1], metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return svc, nil
+	}
+
+	svc, err := kubeClient.CoreV1().Services(app.Namespace).Get(context.TODO(), getDefaultUIServiceName(app), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if svc.Spec.Type != apiv1.ServiceTypeLoadBalancer {
+		return nil, nil
+	}
+	return svc, nil
+}
+
+// loadBalancerAddress returns the first resolved external IP or hostname for a Service, or "" if its
+// LoadBalancer status hasn't been populated by the cloud provider yet.
+func loadBalancerAddress(svc *apiv1.Service) string {
+	if svc == nil || len(svc.Status.LoadBalancer.Ingress) == 0 {
+		return ""
+	}
+	lbIngress := svc.Status.LoadBalancer.Ingress[0]
+	if lbIngress.Hostname != "" {
+		return lbIngress.Hostname
+	}
+	return lbIngress.IP
+}
+
+// syncPublishedServiceStatus resolves the published Service's external address (see
+// getPublishedServiceSource) and copies it onto both app.Status.DriverInfo.WebUIIngressAddress and the
+// status.loadBalancer.ingress of the Spark UI Ingress, so `kubectl get sparkapplication` surfaces a
+// stable externally-reachable URL without users having to separately look up the Service or Ingress.
+// The Ingress status is only written on API servers that serve networking.k8s.io/v1 and only if the
+// Ingress already exists; an extensions/v1beta1 Ingress's status is left unchanged.
+// It returns the resolved address, or "" if the load balancer hasn't been provisioned yet.
+func syncPublishedServiceStatus(app *v1beta2.SparkApplication, kubeClient clientset.Interface) (string, error) {
+	svc, err := getPublishedServiceSource(app, kubeClient)
+	if err != nil {
+		return "", err
+	}
+	address := loadBalancerAddress(svc)
+	if address == "" {
+		return "", nil
+	}
+
+	app.Status.DriverInfo.WebUIIngressAddress = address
+
+	if !supportsNetworkingV1Ingress(kubeClient) {
+		return address, nil
+	}
+	ingressName := getDefaultUIIngressName(app)
+	ingress, err := kubeClient.NetworkingV1().Ingresses(app.Namespace).Get(context.TODO(), ingressName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return address, nil
+		}
+		return "", err
+	}
+
+	desired := apiv1.LoadBalancerIngress{IP: svc.Status.LoadBalancer.Ingress[0].IP, Hostname: svc.Status.LoadBalancer.Ingress[0].Hostname}
+	if len(ingress.Status.LoadBalancer.Ingress) == 1 {
+		observed := ingress.Status.LoadBalancer.Ingress[0]
+		if observed.IP == desired.IP && observed.Hostname == desired.Hostname {
+			return address, nil
+		}
+	}
+	ingress.Status.LoadBalancer.Ingress = []apiv1.LoadBalancerIngress{desired}
+	glog.Infof("Publishing address %s onto Ingress %s status for application %s", address, ingress.Name, app.Name)
+	_, err = kubeClient.NetworkingV1().Ingresses(app.Namespace).UpdateStatus(context.TODO(), ingress, metav1.UpdateOptions{})
+	return address, err
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func TestLoadBalancerAddressPrefersHostname(t *testing.T) {
+	svc := &apiv1.Service{Status: apiv1.ServiceStatus{LoadBalancer: apiv1.LoadBalancerStatus{
+		Ingress: []apiv1.LoadBalancerIngress{{IP: "1.2.3.4", Hostname: "lb.example.com"}},
+	}}}
+	if addr := loadBalancerAddress(svc); addr != "lb.example.com" {
+		t.Errorf("expected hostname to be preferred, got %q", addr)
+	}
+}
+
+func TestLoadBalancerAddressFallsBackToIP(t *testing.T) {
+	svc := &apiv1.Service{Status: apiv1.ServiceStatus{LoadBalancer: apiv1.LoadBalancerStatus{
+		Ingress: []apiv1.LoadBalancerIngress{{IP: "1.2.3.4"}},
+	}}}
+	if addr := loadBalancerAddress(svc); addr != "1.2.3.4" {
+		t.Errorf("expected IP fallback, got %q", addr)
+	}
+}
+
+func TestLoadBalancerAddressEmptyWhenUnresolved(t *testing.T) {
+	if addr := loadBalancerAddress(&apiv1.Service{}); addr != "" {
+		t.Errorf("expected empty address for unresolved LoadBalancer, got %q", addr)
+	}
+	if addr := loadBalancerAddress(nil); addr != "" {
+		t.Errorf("expected empty address for nil Service, got %q", addr)
+	}
+}
+
+func TestGetPublishedServiceSourceDefaultsToUIServiceWhenLoadBalancer(t *testing.T) {
+	app := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"}}
+	svc := &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: getDefaultUIServiceName(app), Namespace: app.Namespace},
+		Spec:       apiv1.ServiceSpec{Type: apiv1.ServiceTypeLoadBalancer},
+	}
+	kubeClient := fake.NewSimpleClientset(svc)
+
+	source, err := getPublishedServiceSource(app, kubeClient)
+	if err != nil {
+		t.Fatalf("getPublishedServiceSource returned error: %v", err)
+	}
+	if source == nil || source.Name != svc.Name {
+		t.Errorf("expected the default UI Service to be returned, got %v", source)
+	}
+}
+
+func TestGetPublishedServiceSourceIgnoresNonLoadBalancerUIService(t *testing.T) {
+	app := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"}}
+	svc := &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: getDefaultUIServiceName(app), Namespace: app.Namespace},
+		Spec:       apiv1.ServiceSpec{Type: apiv1.ServiceTypeClusterIP},
+	}
+	kubeClient := fake.NewSimpleClientset(svc)
+
+	source, err := getPublishedServiceSource(app, kubeClient)
+	if err != nil {
+		t.Fatalf("getPublishedServiceSource returned error: %v", err)
+	}
+	if source != nil {
+		t.Errorf("expected no published service source for a ClusterIP UI Service, got %v", source)
+	}
+}
+
+func TestGetPublishedServiceSourceHonorsOverride(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+		Spec: v1beta2.SparkApplicationSpec{
+			SparkUIOptions: &v1beta2.SparkUIOptions{PublishedService: stringPtr("shared/ingress-lb")},
+		},
+	}
+	svc := &apiv1.Service{ObjectMeta: metav1.ObjectMeta{Name: "ingress-lb", Namespace: "shared"}}
+	kubeClient := fake.NewSimpleClientset(svc)
+
+	source, err := getPublishedServiceSource(app, kubeClient)
+	if err != nil {
+		t.Fatalf("getPublishedServiceSource returned error: %v", err)
+	}
+	if source == nil || source.Name != "ingress-lb" || source.Namespace != "shared" {
+		t.Errorf("expected the overridden shared/ingress-lb Service to be returned, got %v", source)
+	}
+}
+
+func TestGetPublishedServiceSourceOverrideMissingIsNotAnError(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+		Spec: v1beta2.SparkApplicationSpec{
+			SparkUIOptions: &v1beta2.SparkUIOptions{PublishedService: stringPtr("shared/ingress-lb")},
+		},
+	}
+	kubeClient := fake.NewSimpleClientset()
+
+	source, err := getPublishedServiceSource(app, kubeClient)
+	if err != nil {
+		t.Fatalf("expected no error for a missing published service, got %v", err)
+	}
+	if source != nil {
+		t.Errorf("expected nil source for a missing published service, got %v", source)
+	}
+}
+
+func TestSyncPublishedServiceStatusSetsDriverInfoAddress(t *testing.T) {
+	app := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"}}
+	svc := &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: getDefaultUIServiceName(app), Namespace: app.Namespace},
+		Spec:       apiv1.ServiceSpec{Type: apiv1.ServiceTypeLoadBalancer},
+		Status: apiv1.ServiceStatus{LoadBalancer: apiv1.LoadBalancerStatus{
+			Ingress: []apiv1.LoadBalancerIngress{{IP: "1.2.3.4"}},
+		}},
+	}
+	kubeClient := fake.NewSimpleClientset(svc)
+
+	address, err := syncPublishedServiceStatus(app, kubeClient)
+	if err != nil {
+		t.Fatalf("syncPublishedServiceStatus returned error: %v", err)
+	}
+	if address != "1.2.3.4" {
+		t.Errorf("expected resolved address 1.2.3.4, got %q", address)
+	}
+	if app.Status.DriverInfo.WebUIIngressAddress != "1.2.3.4" {
+		t.Errorf("expected WebUIIngressAddress to be set, got %q", app.Status.DriverInfo.WebUIIngressAddress)
+	}
+}
+
+func TestSyncPublishedServiceStatusNoopWithoutLoadBalancer(t *testing.T) {
+	app := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"}}
+	kubeClient := fake.NewSimpleClientset()
+
+	address, err := syncPublishedServiceStatus(app, kubeClient)
+	if err != nil {
+		t.Fatalf("syncPublishedServiceStatus returned error: %v", err)
+	}
+	if address != "" {
+		t.Errorf("expected no resolved address, got %q", address)
+	}
+	if app.Status.DriverInfo.WebUIIngressAddress != "" {
+		t.Errorf("expected WebUIIngressAddress to stay unset, got %q", app.Status.DriverInfo.WebUIIngressAddress)
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
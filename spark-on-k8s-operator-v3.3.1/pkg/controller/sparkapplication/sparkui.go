@@ -27,6 +27,7 @@ import (
 
 	apiv1 "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	clientset "k8s.io/client-go/kubernetes"
@@ -69,17 +70,134 @@ type SparkService struct {
 	targetPort         intstr.IntOrString
 	serviceIP          string
 	serviceAnnotations map[string]string
+	// additionalPorts holds any driver ports published on the Service besides the Spark UI port, e.g.
+	// the driver RPC, block manager and Prometheus metrics ports.
+	additionalPorts []apiv1.ServicePort
 }
 
+// networkingV1GroupVersion is the group/version string used to probe API server discovery for
+// support of the stable networking.k8s.io/v1 Ingress API, which replaced extensions/v1beta1 and
+// networking.k8s.io/v1beta1 in Kubernetes 1.22.
+const networkingV1GroupVersion = "networking.k8s.io/v1"
+
 // SparkIngress encapsulates information about the driver UI ingress.
 type SparkIngress struct {
 	ingressName string
 	ingressURL  *url.URL
 	annotations map[string]string
-	ingressTLS  []extensions.IngressTLS
+	ingressTLS  []networkingv1.IngressTLS
+}
+
+// supportsNetworkingV1Ingress reports whether the API server the operator is talking to serves the
+// networking.k8s.io/v1 Ingress resource. Clusters older than Kubernetes 1.19 don't, and must keep
+// being served extensions/v1beta1 Ingresses.
+func supportsNetworkingV1Ingress(kubeClient clientset.Interface) bool {
+	resources, err := kubeClient.Discovery().ServerResourcesForGroupVersion(networkingV1GroupVersion)
+	if err != nil {
+		return false
+	}
+	for _, resource := range resources.APIResources {
+		if resource.Kind == "Ingress" {
+			return true
+		}
+	}
+	return false
 }
 
 func createSparkUIIngress(app *v1beta2.SparkApplication, service SparkService, ingressURL *url.URL, kubeClient clientset.Interface) (*SparkIngress, error) {
+	if err := reconcileSparkUIAuthSecret(app, kubeClient); err != nil {
+		return nil, err
+	}
+	if supportsNetworkingV1Ingress(kubeClient) {
+		return createNetworkingV1SparkUIIngress(app, service, ingressURL, kubeClient)
+	}
+	return createExtensionsV1beta1SparkUIIngress(app, service, ingressURL, kubeClient)
+}
+
+// createNetworkingV1SparkUIIngress creates a networking.k8s.io/v1 Ingress for the Spark UI. This is
+// the code path used on any API server that serves the stable Ingress API (Kubernetes 1.19+).
+func createNetworkingV1SparkUIIngress(app *v1beta2.SparkApplication, service SparkService, ingressURL *url.URL, kubeClient clientset.Interface) (*SparkIngress, error) {
+	flavor := getIngressFlavor(app)
+	ingressResourceAnnotations := getIngressResourceAnnotations(app)
+	ingressTlsHosts := convertIngressTLSToNetworkingV1(getIngressTlsHosts(app))
+
+	ingressURLPath := flavor.subPathPattern(ingressURL.Path)
+	pathType := flavor.pathType()
+
+	ingress := networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            getDefaultUIIngressName(app),
+			Namespace:       app.Namespace,
+			Labels:          getResourceLabels(app),
+			OwnerReferences: []metav1.OwnerReference{*getOwnerReference(app)},
+		},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: getIngressClassName(app),
+			Rules: []networkingv1.IngressRule{{
+				Host: ingressURL.Host,
+				IngressRuleValue: networkingv1.IngressRuleValue{
+					HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{{
+							Backend: networkingv1.IngressBackend{
+								Service: &networkingv1.IngressServiceBackend{
+									Name: service.serviceName,
+									Port: networkingv1.ServiceBackendPort{
+										Name: service.servicePortName,
+									},
+								},
+							},
+							Path:     ingressURLPath,
+							PathType: &pathType,
+						}},
+					},
+				},
+			}},
+		},
+	}
+
+	if len(ingressResourceAnnotations) != 0 {
+		ingress.ObjectMeta.Annotations = ingressResourceAnnotations
+	}
+
+	// If we're serving on a subpath, we need to ensure the flavor's controller rewrites the request
+	// back to "/" before it reaches the Spark UI.
+	if rewrite := flavor.rewriteAnnotations(ingressURL.Path); len(rewrite) != 0 {
+		if ingress.ObjectMeta.Annotations == nil {
+			ingress.ObjectMeta.Annotations = make(map[string]string)
+		}
+		for key, value := range rewrite {
+			ingress.ObjectMeta.Annotations[key] = value
+		}
+	}
+	ingress.Annotations, ingressTlsHosts = applyCertManagerTLS(app, flavor, ingressURL.Host, ingress.Annotations, ingressTlsHosts)
+	if authAnnotations := getAuthAnnotations(app, flavor); len(authAnnotations) != 0 {
+		if ingress.ObjectMeta.Annotations == nil {
+			ingress.ObjectMeta.Annotations = make(map[string]string)
+		}
+		for key, value := range authAnnotations {
+			ingress.ObjectMeta.Annotations[key] = value
+		}
+	}
+	if len(ingressTlsHosts) != 0 {
+		ingress.Spec.TLS = ingressTlsHosts
+	}
+	glog.Infof("Creating a networking.k8s.io/v1 Ingress %s for the Spark UI for application %s", ingress.Name, app.Name)
+	_, err := kubeClient.NetworkingV1().Ingresses(ingress.Namespace).Create(context.TODO(), &ingress, metav1.CreateOptions{})
+
+	if err != nil {
+		return nil, err
+	}
+	return &SparkIngress{
+		ingressName: ingress.Name,
+		ingressURL:  ingressURL,
+		annotations: ingress.Annotations,
+		ingressTLS:  ingress.Spec.TLS,
+	}, nil
+}
+
+// createExtensionsV1beta1SparkUIIngress creates an extensions/v1beta1 Ingress for the Spark UI. It is
+// kept around for API servers older than Kubernetes 1.19 that don't serve networking.k8s.io/v1 yet.
+func createExtensionsV1beta1SparkUIIngress(app *v1beta2.SparkApplication, service SparkService, ingressURL *url.URL, kubeClient clientset.Interface) (*SparkIngress, error) {
 	ingressResourceAnnotations := getIngressResourceAnnotations(app)
 	ingressTlsHosts := getIngressTlsHosts(app)
 
@@ -128,6 +246,14 @@ func createSparkUIIngress(app *v1beta2.SparkApplication, service SparkService, i
 		}
 		ingress.ObjectMeta.Annotations["nginx.ingress.kubernetes.io/rewrite-target"] = "/$2"
 	}
+	if authAnnotations := getAuthAnnotations(app, getIngressFlavor(app)); len(authAnnotations) != 0 {
+		if ingress.ObjectMeta.Annotations == nil {
+			ingress.ObjectMeta.Annotations = make(map[string]string)
+		}
+		for key, value := range authAnnotations {
+			ingress.ObjectMeta.Annotations[key] = value
+		}
+	}
 	if len(ingressTlsHosts) != 0 {
 		ingress.Spec.TLS = ingressTlsHosts
 	}
@@ -141,10 +267,37 @@ func createSparkUIIngress(app *v1beta2.SparkApplication, service SparkService, i
 		ingressName: ingress.Name,
 		ingressURL:  ingressURL,
 		annotations: ingress.Annotations,
-		ingressTLS:  ingress.Spec.TLS,
+		ingressTLS:  convertIngressTLSToNetworkingV1(ingress.Spec.TLS),
 	}, nil
 }
 
+// convertIngressTLSToNetworkingV1 converts the extensions/v1beta1 IngressTLS entries returned by
+// getIngressTlsHosts into their networking.k8s.io/v1 equivalent. The two types are structurally
+// identical; only the package they're declared in differs.
+func convertIngressTLSToNetworkingV1(tls []extensions.IngressTLS) []networkingv1.IngressTLS {
+	if len(tls) == 0 {
+		return nil
+	}
+	converted := make([]networkingv1.IngressTLS, 0, len(tls))
+	for _, entry := range tls {
+		converted = append(converted, networkingv1.IngressTLS{
+			Hosts:      entry.Hosts,
+			SecretName: entry.SecretName,
+		})
+	}
+	return converted
+}
+
+// getIngressClassName returns the IngressClassName that should be set on the generated
+// networking.k8s.io/v1 Ingress, or nil to leave class selection to the cluster's default
+// IngressClass (or the deprecated kubernetes.io/ingress.class annotation).
+func getIngressClassName(app *v1beta2.SparkApplication) *string {
+	if app.Spec.SparkUIOptions == nil {
+		return nil
+	}
+	return app.Spec.SparkUIOptions.IngressClassName
+}
+
 func createSparkUIService(
 	app *v1beta2.SparkApplication,
 	kubeClient clientset.Interface) (*SparkService, error) {
@@ -157,6 +310,21 @@ func createSparkUIService(
 	if err != nil {
 		return nil, fmt.Errorf("invalid Spark UI targetPort: %d", tPort)
 	}
+	driverPorts, err := getDriverNamedPorts(app)
+	if err != nil {
+		return nil, fmt.Errorf("invalid driver port in SparkConf: %v", err)
+	}
+	ports := append([]apiv1.ServicePort{
+		{
+			Name: portName,
+			Port: port,
+			TargetPort: intstr.IntOrString{
+				Type:   intstr.Int,
+				IntVal: tPort,
+			},
+		},
+	}, driverPorts...)
+
 	service := &apiv1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            getDefaultUIServiceName(app),
@@ -165,23 +333,18 @@ func createSparkUIService(
 			OwnerReferences: []metav1.OwnerReference{*getOwnerReference(app)},
 		},
 		Spec: apiv1.ServiceSpec{
-			Ports: []apiv1.ServicePort{
-				{
-					Name: portName,
-					Port: port,
-					TargetPort: intstr.IntOrString{
-						Type:   intstr.Int,
-						IntVal: tPort,
-					},
-				},
-			},
+			Ports: ports,
 			Selector: map[string]string{
 				config.SparkAppNameLabel: app.Name,
 				config.SparkRoleLabel:    config.SparkDriverRole,
 			},
-			Type: getUIServiceType(app),
+			Type:                     getUIServiceType(app),
+			PublishNotReadyAddresses: shouldPublishNotReadyAddresses(app),
 		},
 	}
+	if isDriverServiceHeadless(app) {
+		service.Spec.ClusterIP = apiv1.ClusterIPNone
+	}
 
 	serviceAnnotations := getServiceAnnotations(app)
 	if len(serviceAnnotations) != 0 {
@@ -202,6 +365,7 @@ func createSparkUIService(
 		targetPort:         service.Spec.Ports[0].TargetPort,
 		serviceIP:          service.Spec.ClusterIP,
 		serviceAnnotations: serviceAnnotations,
+		additionalPorts:    service.Spec.Ports[1:],
 	}, nil
 }
 
@@ -0,0 +1,146 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+
+	"github.com/golang/glog"
+	"golang.org/x/crypto/bcrypt"
+
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+const defaultBasicAuthSecretKey = "auth"
+
+// getAuthAnnotations returns the annotations the given ingress flavor requires to enforce the
+// application's SparkUIOptions.Auth configuration, or nil if no auth is configured.
+func getAuthAnnotations(app *v1beta2.SparkApplication, flavor IngressFlavor) map[string]string {
+	if app.Spec.SparkUIOptions == nil || app.Spec.SparkUIOptions.Auth == nil {
+		return nil
+	}
+	auth := app.Spec.SparkUIOptions.Auth
+	annotations := make(map[string]string)
+	if auth.Basic != nil {
+		for key, value := range flavor.basicAuthAnnotations(auth.Basic.SecretName) {
+			annotations[key] = value
+		}
+	}
+	if auth.OAuth2Proxy != nil {
+		for key, value := range flavor.forwardAuthAnnotations(auth.OAuth2Proxy.AuthURL, auth.OAuth2Proxy.SigninURL) {
+			annotations[key] = value
+		}
+	}
+	for key, value := range flavor.whitelistAnnotations(auth.IPWhitelist) {
+		annotations[key] = value
+	}
+	return annotations
+}
+
+// basicAuthSecretKey returns the Secret key that holds the htpasswd data for the given BasicAuthOptions.
+func basicAuthSecretKey(basic *v1beta2.BasicAuthOptions) string {
+	if basic.SecretKey != nil {
+		return *basic.SecretKey
+	}
+	return defaultBasicAuthSecretKey
+}
+
+// reconcileSparkUIAuthSecret creates or updates the htpasswd Secret backing SparkUIOptions.Auth.Basic
+// when the application supplies inline Username/Password. If the user instead points SecretName at a
+// Secret they manage themselves (no inline credentials), this is a no-op.
+func reconcileSparkUIAuthSecret(app *v1beta2.SparkApplication, kubeClient clientset.Interface) error {
+	if app.Spec.SparkUIOptions == nil || app.Spec.SparkUIOptions.Auth == nil || app.Spec.SparkUIOptions.Auth.Basic == nil {
+		return nil
+	}
+	basic := app.Spec.SparkUIOptions.Auth.Basic
+	if basic.Username == nil || basic.Password == nil {
+		return nil
+	}
+
+	htpasswd, err := renderHtpasswd(*basic.Username, *basic.Password)
+	if err != nil {
+		return err
+	}
+	secret := &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            basic.SecretName,
+			Namespace:       app.Namespace,
+			Labels:          getResourceLabels(app),
+			OwnerReferences: []metav1.OwnerReference{*getOwnerReference(app)},
+		},
+		Type: apiv1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			basicAuthSecretKey(basic): htpasswd,
+		},
+	}
+
+	existing, err := kubeClient.CoreV1().Secrets(app.Namespace).Get(context.TODO(), secret.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		glog.Infof("Creating Secret %s with Spark UI Basic auth credentials for application %s", secret.Name, app.Name)
+		_, err = kubeClient.CoreV1().Secrets(app.Namespace).Create(context.TODO(), secret, metav1.CreateOptions{})
+		return err
+	}
+
+	existing.Data = secret.Data
+	glog.Infof("Updating Secret %s with Spark UI Basic auth credentials for application %s", secret.Name, app.Name)
+	_, err = kubeClient.CoreV1().Secrets(app.Namespace).Update(context.TODO(), existing, metav1.UpdateOptions{})
+	return err
+}
+
+// DeleteSparkUIResources cleans up the Spark UI resources that aren't already covered by
+// OwnerReference-driven garbage collection once a SparkApplication is deleted. The controller's
+// SparkApplication deletion handling calls this before removing the application's finalizer.
+func DeleteSparkUIResources(app *v1beta2.SparkApplication, kubeClient clientset.Interface) error {
+	return deleteSparkUIAuthSecret(app, kubeClient)
+}
+
+// deleteSparkUIAuthSecret removes the Basic auth Secret the operator created for this application, if
+// any. It's called when the SparkApplication is deleted so inline credentials don't outlive it. Secrets
+// the user pointed SecretName at without supplying inline credentials are left alone, since the
+// operator doesn't own them.
+func deleteSparkUIAuthSecret(app *v1beta2.SparkApplication, kubeClient clientset.Interface) error {
+	if app.Spec.SparkUIOptions == nil || app.Spec.SparkUIOptions.Auth == nil || app.Spec.SparkUIOptions.Auth.Basic == nil {
+		return nil
+	}
+	basic := app.Spec.SparkUIOptions.Auth.Basic
+	if basic.Username == nil || basic.Password == nil {
+		return nil
+	}
+	err := kubeClient.CoreV1().Secrets(app.Namespace).Delete(context.TODO(), basic.SecretName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// renderHtpasswd renders a single-user htpasswd file using bcrypt, which nginx-ingress, Traefik and
+// most other ingress controllers that support file-based Basic auth accept.
+func renderHtpasswd(username, password string) ([]byte, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(username + ":" + string(hash) + "\n"), nil
+}
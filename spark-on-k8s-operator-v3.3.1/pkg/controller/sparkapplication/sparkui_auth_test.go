@@ -0,0 +1,104 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func newTestAppWithAuth(auth *v1beta2.SparkUIAuthOptions) *v1beta2.SparkApplication {
+	return &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+		Spec: v1beta2.SparkApplicationSpec{
+			SparkUIOptions: &v1beta2.SparkUIOptions{Auth: auth},
+		},
+	}
+}
+
+func TestGetAuthAnnotationsNginx(t *testing.T) {
+	app := newTestAppWithAuth(&v1beta2.SparkUIAuthOptions{
+		Basic:       &v1beta2.BasicAuthOptions{SecretName: "ui-auth"},
+		IPWhitelist: []string{"10.0.0.0/8", "192.168.0.0/16"},
+	})
+
+	annotations := getAuthAnnotations(app, IngressFlavorNginx)
+
+	if annotations["nginx.ingress.kubernetes.io/auth-type"] != "basic" {
+		t.Errorf("expected basic auth-type annotation, got %q", annotations["nginx.ingress.kubernetes.io/auth-type"])
+	}
+	if annotations["nginx.ingress.kubernetes.io/auth-secret"] != "ui-auth" {
+		t.Errorf("expected auth-secret annotation to reference ui-auth, got %q", annotations["nginx.ingress.kubernetes.io/auth-secret"])
+	}
+	if annotations["nginx.ingress.kubernetes.io/whitelist-source-range"] != "10.0.0.0/8,192.168.0.0/16" {
+		t.Errorf("unexpected whitelist annotation: %q", annotations["nginx.ingress.kubernetes.io/whitelist-source-range"])
+	}
+}
+
+func TestGetAuthAnnotationsNilWhenUnconfigured(t *testing.T) {
+	app := newTestAppWithAuth(nil)
+	if annotations := getAuthAnnotations(app, IngressFlavorNginx); annotations != nil {
+		t.Errorf("expected nil annotations when Auth is unconfigured, got %v", annotations)
+	}
+}
+
+func TestDeleteSparkUIResourcesRemovesOperatorManagedSecret(t *testing.T) {
+	username, password := "admin", "s3cr3t"
+	app := newTestAppWithAuth(&v1beta2.SparkUIAuthOptions{
+		Basic: &v1beta2.BasicAuthOptions{SecretName: "ui-auth", Username: &username, Password: &password},
+	})
+	kubeClient := fake.NewSimpleClientset()
+	if err := reconcileSparkUIAuthSecret(app, kubeClient); err != nil {
+		t.Fatalf("reconcileSparkUIAuthSecret returned error: %v", err)
+	}
+	if _, err := kubeClient.CoreV1().Secrets(app.Namespace).Get(context.TODO(), "ui-auth", metav1.GetOptions{}); err != nil {
+		t.Fatalf("expected auth Secret to exist after reconcile, got error: %v", err)
+	}
+
+	if err := DeleteSparkUIResources(app, kubeClient); err != nil {
+		t.Fatalf("DeleteSparkUIResources returned error: %v", err)
+	}
+
+	_, err := kubeClient.CoreV1().Secrets(app.Namespace).Get(context.TODO(), "ui-auth", metav1.GetOptions{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected auth Secret to be deleted, got error: %v", err)
+	}
+}
+
+func TestDeleteSparkUIResourcesLeavesUserManagedSecretAlone(t *testing.T) {
+	app := newTestAppWithAuth(&v1beta2.SparkUIAuthOptions{
+		Basic: &v1beta2.BasicAuthOptions{SecretName: "user-managed-secret"},
+	})
+	kubeClient := fake.NewSimpleClientset(&apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "user-managed-secret", Namespace: app.Namespace},
+	})
+
+	if err := DeleteSparkUIResources(app, kubeClient); err != nil {
+		t.Fatalf("DeleteSparkUIResources returned error: %v", err)
+	}
+
+	if _, err := kubeClient.CoreV1().Secrets(app.Namespace).Get(context.TODO(), "user-managed-secret", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected user-managed Secret to be left alone, got error: %v", err)
+	}
+}
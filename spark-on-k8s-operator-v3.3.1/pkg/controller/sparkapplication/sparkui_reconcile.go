@@ -0,0 +1,333 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"time"
+
+	"github.com/golang/glog"
+
+	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+// serviceDeletePollInterval and serviceDeleteTimeout bound how long
+// waitForServiceDeleted polls the API server for a Service's deletion to be observed before giving up
+// and surfacing an error, rather than racing ahead into a recreate that would hit a conflict.
+const (
+	serviceDeletePollInterval = 200 * time.Millisecond
+	serviceDeleteTimeout      = 30 * time.Second
+)
+
+// waitForServiceDeleted polls until the named Service is gone (Get returns NotFound) or
+// serviceDeleteTimeout elapses, whichever comes first.
+func waitForServiceDeleted(namespace, name string, kubeClient clientset.Interface) error {
+	return wait.PollImmediate(serviceDeletePollInterval, serviceDeleteTimeout, func() (bool, error) {
+		_, err := kubeClient.CoreV1().Services(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return false, nil
+	})
+}
+
+// reconcileSparkUIService brings the Spark UI Service in line with the application's current
+// SparkUIOptions/DriverServiceOptions on every sync, instead of only creating it once at submission
+// time. If no Service exists yet it's created as usual; if one exists its mutable fields (ports, type,
+// publishNotReadyAddresses, annotations, labels) are written back with a single Update of the whole
+// Service (no patch requests are issued), and changes to the immutable ClusterIP field (switching
+// Headless on or off) are handled by deleting and recreating the Service. Since this already runs on
+// every sync, it also doubles as the "watch the Service and publish its address" loop: once the
+// Service is settled, it syncs the resolved LoadBalancer address (see syncPublishedServiceStatus)
+// onto the application status and onto the ingress status if the Ingress already exists.
+func reconcileSparkUIService(app *v1beta2.SparkApplication, kubeClient clientset.Interface) (*SparkService, error) {
+	service, err := reconcileSparkUIServiceSpec(app, kubeClient)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := syncPublishedServiceStatus(app, kubeClient); err != nil {
+		return nil, err
+	}
+	return service, nil
+}
+
+// reconcileSparkUIServiceSpec performs the actual Service create/diff/update; split out from
+// reconcileSparkUIService so the published-service status sync always runs afterward, on every return
+// path, without being duplicated at each early return below.
+func reconcileSparkUIServiceSpec(app *v1beta2.SparkApplication, kubeClient clientset.Interface) (*SparkService, error) {
+	name := getDefaultUIServiceName(app)
+	existing, err := kubeClient.CoreV1().Services(app.Namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return createSparkUIService(app, kubeClient)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	portName := getUIServicePortName(app)
+	port, err := getUIServicePort(app)
+	if err != nil {
+		return nil, err
+	}
+	tPort, err := getUITargetPort(app)
+	if err != nil {
+		return nil, err
+	}
+	driverPorts, err := getDriverNamedPorts(app)
+	if err != nil {
+		return nil, err
+	}
+	desiredPorts := append([]apiv1.ServicePort{{
+		Name: portName,
+		Port: port,
+		TargetPort: intstr.IntOrString{
+			Type:   intstr.Int,
+			IntVal: tPort,
+		},
+	}}, driverPorts...)
+
+	desiredHeadless := isDriverServiceHeadless(app)
+	observedHeadless := existing.Spec.ClusterIP == apiv1.ClusterIPNone
+	if desiredHeadless != observedHeadless {
+		// ClusterIP is immutable once set; the only way to flip headless-ness is to delete and
+		// recreate the Service. Wait for the delete to actually be observed before recreating, so we
+		// don't race a dangling Service with the same name that's still being torn down (e.g. by a
+		// finalizer) and get back a stale object or a conflict on Create.
+		glog.Infof("Recreating Service %s for the Spark UI for application %s to change headless mode", name, app.Name)
+		if err := kubeClient.CoreV1().Services(app.Namespace).Delete(context.TODO(), name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		if err := waitForServiceDeleted(app.Namespace, name, kubeClient); err != nil {
+			return nil, err
+		}
+		return createSparkUIService(app, kubeClient)
+	}
+
+	desiredAnnotations := getServiceAnnotations(app)
+	desiredLabels := getResourceLabels(app)
+	needsUpdate := !reflect.DeepEqual(existing.Spec.Ports, desiredPorts) ||
+		existing.Spec.PublishNotReadyAddresses != shouldPublishNotReadyAddresses(app) ||
+		!reflect.DeepEqual(existing.Annotations, desiredAnnotations) ||
+		!reflect.DeepEqual(existing.Labels, desiredLabels) ||
+		existing.Spec.Type != getUIServiceType(app)
+
+	if needsUpdate {
+		existing.Spec.Ports = desiredPorts
+		existing.Spec.PublishNotReadyAddresses = shouldPublishNotReadyAddresses(app)
+		existing.Spec.Type = getUIServiceType(app)
+		existing.Annotations = desiredAnnotations
+		existing.Labels = desiredLabels
+		glog.Infof("Updating Service %s for the Spark UI for application %s", name, app.Name)
+		existing, err = kubeClient.CoreV1().Services(app.Namespace).Update(context.TODO(), existing, metav1.UpdateOptions{})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &SparkService{
+		serviceName:        existing.Name,
+		serviceType:        existing.Spec.Type,
+		servicePort:        existing.Spec.Ports[0].Port,
+		servicePortName:    existing.Spec.Ports[0].Name,
+		targetPort:         existing.Spec.Ports[0].TargetPort,
+		serviceIP:          existing.Spec.ClusterIP,
+		serviceAnnotations: desiredAnnotations,
+		additionalPorts:    existing.Spec.Ports[1:],
+	}, nil
+}
+
+// reconcileSparkUIIngress brings the Spark UI Ingress in line with the application's current
+// SparkUIOptions on every sync (annotations, TLS, hostname), instead of only creating it once at
+// submission time. Host, path and annotation changes are applied with a single Update of the whole
+// Ingress; no patch requests are issued. On API servers that don't serve
+// networking.k8s.io/v1, reconciliation falls back to reconcileExtensionsV1beta1SparkUIIngress.
+func reconcileSparkUIIngress(app *v1beta2.SparkApplication, service SparkService, ingressURL *url.URL, kubeClient clientset.Interface) (*SparkIngress, error) {
+	if err := reconcileSparkUIAuthSecret(app, kubeClient); err != nil {
+		return nil, err
+	}
+
+	if !supportsNetworkingV1Ingress(kubeClient) {
+		return reconcileExtensionsV1beta1SparkUIIngress(app, service, ingressURL, kubeClient)
+	}
+
+	name := getDefaultUIIngressName(app)
+	existing, err := kubeClient.NetworkingV1().Ingresses(app.Namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return createSparkUIIngress(app, service, ingressURL, kubeClient)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	flavor := getIngressFlavor(app)
+	ingressURLPath := flavor.subPathPattern(ingressURL.Path)
+	pathType := flavor.pathType()
+
+	desiredRules := []networkingv1.IngressRule{{
+		Host: ingressURL.Host,
+		IngressRuleValue: networkingv1.IngressRuleValue{
+			HTTP: &networkingv1.HTTPIngressRuleValue{
+				Paths: []networkingv1.HTTPIngressPath{{
+					Backend: networkingv1.IngressBackend{
+						Service: &networkingv1.IngressServiceBackend{
+							Name: service.serviceName,
+							Port: networkingv1.ServiceBackendPort{Name: service.servicePortName},
+						},
+					},
+					Path:     ingressURLPath,
+					PathType: &pathType,
+				}},
+			},
+		},
+	}}
+
+	annotations := getIngressResourceAnnotations(app)
+	if rewrite := flavor.rewriteAnnotations(ingressURL.Path); len(rewrite) != 0 {
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		for key, value := range rewrite {
+			annotations[key] = value
+		}
+	}
+	if authAnnotations := getAuthAnnotations(app, flavor); len(authAnnotations) != 0 {
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		for key, value := range authAnnotations {
+			annotations[key] = value
+		}
+	}
+	tls := convertIngressTLSToNetworkingV1(getIngressTlsHosts(app))
+	annotations, tls = applyCertManagerTLS(app, flavor, ingressURL.Host, annotations, tls)
+
+	needsUpdate := !reflect.DeepEqual(existing.Spec.Rules, desiredRules) ||
+		!reflect.DeepEqual(existing.Annotations, annotations) ||
+		!reflect.DeepEqual(existing.Spec.TLS, tls) ||
+		!reflect.DeepEqual(existing.Spec.IngressClassName, getIngressClassName(app))
+
+	if needsUpdate {
+		existing.Spec.Rules = desiredRules
+		existing.Annotations = annotations
+		existing.Spec.TLS = tls
+		existing.Spec.IngressClassName = getIngressClassName(app)
+		glog.Infof("Updating Ingress %s for the Spark UI for application %s", name, app.Name)
+		existing, err = kubeClient.NetworkingV1().Ingresses(app.Namespace).Update(context.TODO(), existing, metav1.UpdateOptions{})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &SparkIngress{
+		ingressName: existing.Name,
+		ingressURL:  ingressURL,
+		annotations: existing.Annotations,
+		ingressTLS:  existing.Spec.TLS,
+	}, nil
+}
+
+// reconcileExtensionsV1beta1SparkUIIngress is the reconcile-on-every-sync counterpart of
+// createExtensionsV1beta1SparkUIIngress, used on API servers older than Kubernetes 1.19 that don't
+// serve networking.k8s.io/v1 yet. It applies the same get-or-create-then-diff approach as
+// reconcileSparkUIIngress so that, unlike a bare Create, it doesn't fail with AlreadyExists on every
+// sync after the first.
+func reconcileExtensionsV1beta1SparkUIIngress(app *v1beta2.SparkApplication, service SparkService, ingressURL *url.URL, kubeClient clientset.Interface) (*SparkIngress, error) {
+	name := getDefaultUIIngressName(app)
+	existing, err := kubeClient.ExtensionsV1beta1().Ingresses(app.Namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return createExtensionsV1beta1SparkUIIngress(app, service, ingressURL, kubeClient)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ingressURLPath := ingressURL.Path
+	if ingressURLPath != "" && ingressURLPath != "/" {
+		ingressURLPath = ingressURLPath + "(/|$)(.*)"
+	}
+
+	desiredRules := []extensions.IngressRule{{
+		Host: ingressURL.Host,
+		IngressRuleValue: extensions.IngressRuleValue{
+			HTTP: &extensions.HTTPIngressRuleValue{
+				Paths: []extensions.HTTPIngressPath{{
+					Backend: extensions.IngressBackend{
+						ServiceName: service.serviceName,
+						ServicePort: intstr.IntOrString{
+							Type:   intstr.Int,
+							IntVal: service.servicePort,
+						},
+					},
+					Path: ingressURLPath,
+				}},
+			},
+		},
+	}}
+
+	annotations := getIngressResourceAnnotations(app)
+	if ingressURL.Path != "" && ingressURL.Path != "/" {
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations["nginx.ingress.kubernetes.io/rewrite-target"] = "/$2"
+	}
+	if authAnnotations := getAuthAnnotations(app, getIngressFlavor(app)); len(authAnnotations) != 0 {
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		for key, value := range authAnnotations {
+			annotations[key] = value
+		}
+	}
+	tls := getIngressTlsHosts(app)
+
+	needsUpdate := !reflect.DeepEqual(existing.Spec.Rules, desiredRules) ||
+		!reflect.DeepEqual(existing.Annotations, annotations) ||
+		!reflect.DeepEqual(existing.Spec.TLS, tls)
+
+	if needsUpdate {
+		existing.Spec.Rules = desiredRules
+		existing.Annotations = annotations
+		existing.Spec.TLS = tls
+		glog.Infof("Updating Ingress %s for the Spark UI for application %s", name, app.Name)
+		existing, err = kubeClient.ExtensionsV1beta1().Ingresses(app.Namespace).Update(context.TODO(), existing, metav1.UpdateOptions{})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &SparkIngress{
+		ingressName: existing.Name,
+		ingressURL:  ingressURL,
+		annotations: existing.Annotations,
+		ingressTLS:  convertIngressTLSToNetworkingV1(existing.Spec.TLS),
+	}, nil
+}
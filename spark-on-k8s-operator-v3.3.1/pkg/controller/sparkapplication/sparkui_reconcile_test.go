@@ -0,0 +1,103 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func TestWaitForServiceDeletedReturnsImmediatelyWhenAlreadyGone(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	if err := waitForServiceDeleted("default", "does-not-exist", kubeClient); err != nil {
+		t.Errorf("expected no error for an already-absent Service, got %v", err)
+	}
+}
+
+func TestWaitForServiceDeletedObservesAsyncDeletion(t *testing.T) {
+	svc := &apiv1.Service{ObjectMeta: metav1.ObjectMeta{Name: "spark-ui", Namespace: "default"}}
+	kubeClient := fake.NewSimpleClientset(svc)
+
+	go func() {
+		time.Sleep(2 * serviceDeletePollInterval)
+		_ = kubeClient.CoreV1().Services(svc.Namespace).Delete(context.TODO(), svc.Name, metav1.DeleteOptions{})
+	}()
+
+	if err := waitForServiceDeleted(svc.Namespace, svc.Name, kubeClient); err != nil {
+		t.Errorf("expected the async deletion to be observed, got %v", err)
+	}
+}
+
+func TestReconcileExtensionsV1beta1SparkUIIngressCreatesWhenMissing(t *testing.T) {
+	app := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"}}
+	service := SparkService{serviceName: getDefaultUIServiceName(app), servicePort: 4040, servicePortName: "spark-driver-ui-port"}
+	ingressURL := &url.URL{Host: "spark.example.com", Path: "/"}
+	kubeClient := fake.NewSimpleClientset()
+
+	ingress, err := reconcileExtensionsV1beta1SparkUIIngress(app, service, ingressURL, kubeClient)
+	if err != nil {
+		t.Fatalf("reconcileExtensionsV1beta1SparkUIIngress returned error: %v", err)
+	}
+	if ingress.ingressName != getDefaultUIIngressName(app) {
+		t.Errorf("unexpected ingress name: %q", ingress.ingressName)
+	}
+	if _, err := kubeClient.ExtensionsV1beta1().Ingresses(app.Namespace).Get(context.TODO(), getDefaultUIIngressName(app), metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the Ingress to have been created, got error: %v", err)
+	}
+}
+
+// TestReconcileExtensionsV1beta1SparkUIIngressUpdatesExistingInPlace guards against regressing to a
+// bare Create: once an Ingress already exists for this application, a sync must update it in place
+// instead of failing with AlreadyExists on every subsequent reconcile.
+func TestReconcileExtensionsV1beta1SparkUIIngressUpdatesExistingInPlace(t *testing.T) {
+	app := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"}}
+	service := SparkService{serviceName: getDefaultUIServiceName(app), servicePort: 4040, servicePortName: "spark-driver-ui-port"}
+	ingressURL := &url.URL{Host: "spark.example.com", Path: "/"}
+
+	existing := &extensions.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: getDefaultUIIngressName(app), Namespace: app.Namespace},
+		Spec: extensions.IngressSpec{
+			Rules: []extensions.IngressRule{{Host: "stale.example.com"}},
+		},
+	}
+	kubeClient := fake.NewSimpleClientset(existing)
+
+	ingress, err := reconcileExtensionsV1beta1SparkUIIngress(app, service, ingressURL, kubeClient)
+	if err != nil {
+		t.Fatalf("reconcileExtensionsV1beta1SparkUIIngress returned error: %v", err)
+	}
+	if ingress.ingressURL.Host != "spark.example.com" {
+		t.Errorf("expected the returned ingress to reflect the new host, got %q", ingress.ingressURL.Host)
+	}
+
+	updated, err := kubeClient.ExtensionsV1beta1().Ingresses(app.Namespace).Get(context.TODO(), getDefaultUIIngressName(app), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to fetch updated Ingress: %v", err)
+	}
+	if len(updated.Spec.Rules) != 1 || updated.Spec.Rules[0].Host != "spark.example.com" {
+		t.Errorf("expected the existing Ingress to be updated in place with the new host, got %+v", updated.Spec.Rules)
+	}
+}
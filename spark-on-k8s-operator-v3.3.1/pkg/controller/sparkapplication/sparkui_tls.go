@@ -0,0 +1,93 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+
+	networkingv1 "k8s.io/api/networking/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+const (
+	certManagerClusterIssuerAnnotation = "cert-manager.io/cluster-issuer"
+	certManagerIssuerAnnotation        = "cert-manager.io/issuer"
+
+	defaultCertManagerIssuerKind = "ClusterIssuer"
+)
+
+// getUITLSSecretName returns the name of the Secret cert-manager should write the Spark UI's
+// certificate to. It's derived from the application name (the Secret lives in the application's
+// namespace) so it doesn't collide with other SparkApplications' UI certificates in the same namespace.
+func getUITLSSecretName(app *v1beta2.SparkApplication) string {
+	return fmt.Sprintf("%s-ui-tls", app.Name)
+}
+
+// getCertManagerAnnotations returns the cert-manager annotations that should be applied to the Spark
+// UI ingress to have cert-manager provision a certificate for it, or nil if TLS isn't configured.
+func getCertManagerAnnotations(app *v1beta2.SparkApplication) map[string]string {
+	if app.Spec.SparkUIOptions == nil || app.Spec.SparkUIOptions.TLS == nil {
+		return nil
+	}
+	tls := app.Spec.SparkUIOptions.TLS
+	issuerKind := defaultCertManagerIssuerKind
+	if tls.IssuerKind != nil {
+		issuerKind = *tls.IssuerKind
+	}
+	switch issuerKind {
+	case "Issuer":
+		return map[string]string{certManagerIssuerAnnotation: tls.IssuerName}
+	default:
+		return map[string]string{certManagerClusterIssuerAnnotation: tls.IssuerName}
+	}
+}
+
+// buildUITLSHosts returns the networking.k8s.io/v1 IngressTLS entry that requests cert-manager
+// provision a certificate covering the ingress host plus any configured ExtraSANs, or nil if TLS via
+// cert-manager isn't configured for this application.
+func buildUITLSHosts(app *v1beta2.SparkApplication, ingressHost string) []networkingv1.IngressTLS {
+	if app.Spec.SparkUIOptions == nil || app.Spec.SparkUIOptions.TLS == nil {
+		return nil
+	}
+	hosts := append([]string{ingressHost}, app.Spec.SparkUIOptions.TLS.ExtraSANs...)
+	return []networkingv1.IngressTLS{{
+		Hosts:      hosts,
+		SecretName: getUITLSSecretName(app),
+	}}
+}
+
+// applyCertManagerTLS layers cert-manager annotations and the generated IngressTLS entry onto an
+// Ingress that's about to be created or updated for the Spark UI. It's a no-op if the application
+// doesn't request cert-manager TLS.
+func applyCertManagerTLS(app *v1beta2.SparkApplication, flavor IngressFlavor, ingressHost string, annotations map[string]string, tls []networkingv1.IngressTLS) (map[string]string, []networkingv1.IngressTLS) {
+	if app.Spec.SparkUIOptions == nil || app.Spec.SparkUIOptions.TLS == nil {
+		return annotations, tls
+	}
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	for key, value := range getCertManagerAnnotations(app) {
+		annotations[key] = value
+	}
+	if app.Spec.SparkUIOptions.TLS.ForceHTTPSRedirect {
+		for key, value := range flavor.forceHTTPSRedirectAnnotations() {
+			annotations[key] = value
+		}
+	}
+	return annotations, append(tls, buildUITLSHosts(app, ingressHost)...)
+}